@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"math/rand"
+
+	"go.uber.org/zap"
+
+	"github.com/gotd/td/tg"
+)
+
+// ActionContext carries everything an Action needs to react to a single
+// incoming message.
+type ActionContext struct {
+	Troll  *Troll
+	Target *target
+
+	Peer    tg.InputPeerUser
+	Message *tg.Message
+
+	Logger *zap.Logger
+	// Rand is private to this ActionContext and safe for unsynchronized
+	// use by a single Action.
+	Rand *rand.Rand
+}
+
+// Action is a single reaction to an incoming message, e.g. replying with a
+// sticker or revoking it. Actions are selected per-message by a Policy.
+type Action interface {
+	Apply(ctx context.Context, actx ActionContext) error
+}
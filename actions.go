@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/gotd/td/tg"
+)
+
+// StickerReply answers with the last sticker of the target's fetched
+// sticker set.
+type StickerReply struct{}
+
+// Apply implements Action.
+func (StickerReply) Apply(ctx context.Context, actx ActionContext) error {
+	sticker, ok := actx.Target.lastSticker()
+	if !ok {
+		return xerrors.Errorf("target %q: no sticker available", actx.Target.domain)
+	}
+	return actx.Troll.replySticker(ctx, actx.Target, actx.Peer, actx.Message.ID, sticker)
+}
+
+// RandomStickerFromSet answers with an arbitrary sticker of the target's
+// fetched sticker set, rather than always the last one.
+type RandomStickerFromSet struct{}
+
+// Apply implements Action.
+func (RandomStickerFromSet) Apply(ctx context.Context, actx ActionContext) error {
+	sticker, ok := actx.Target.randomSticker(actx.Rand)
+	if !ok {
+		return xerrors.Errorf("target %q: no sticker available", actx.Target.domain)
+	}
+	return actx.Troll.replySticker(ctx, actx.Target, actx.Peer, actx.Message.ID, sticker)
+}
+
+// ForwardAndRevoke forwards the message to Saved Messages and then revokes
+// it for everyone.
+type ForwardAndRevoke struct{}
+
+// Apply implements Action.
+func (ForwardAndRevoke) Apply(ctx context.Context, actx ActionContext) error {
+	return actx.Troll.forwardAndRevoke(ctx, actx.Target, actx.Peer, actx.Message.ID)
+}
+
+// EchoReversed replies with the incoming message's text, reversed.
+type EchoReversed struct{}
+
+// Apply implements Action.
+func (EchoReversed) Apply(ctx context.Context, actx ActionContext) error {
+	_, err := actx.Troll.sender.To(&actx.Peer).
+		Reply(actx.Message.ID).
+		Text(ctx, reverseString(actx.Message.Message))
+	return err
+}
+
+func reverseString(s string) string {
+	r := []rune(s)
+	for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+		r[i], r[j] = r[j], r[i]
+	}
+	return string(r)
+}
+
+// ReactWithEmoji reacts to the incoming message with a fixed emoji.
+type ReactWithEmoji struct {
+	Emoji string
+}
+
+// Apply implements Action.
+func (a ReactWithEmoji) Apply(ctx context.Context, actx ActionContext) error {
+	_, err := actx.Troll.raw.MessagesSendReaction(ctx, &tg.MessagesSendReactionRequest{
+		Peer:     &actx.Peer,
+		MsgID:    actx.Message.ID,
+		Reaction: []tg.ReactionClass{&tg.ReactionEmoji{Emoticon: a.Emoji}},
+	})
+	return err
+}
+
+// TypingForever keeps sending the "typing..." action until Duration has
+// elapsed or ctx is cancelled.
+type TypingForever struct {
+	Duration time.Duration
+}
+
+// Apply implements Action.
+func (a TypingForever) Apply(ctx context.Context, actx ActionContext) error {
+	const typingActionTTL = 4 * time.Second
+
+	deadline := time.Now().Add(a.Duration)
+	ticker := time.NewTicker(typingActionTTL)
+	defer ticker.Stop()
+
+	for {
+		_, err := actx.Troll.raw.MessagesSetTyping(ctx, &tg.MessagesSetTypingRequest{
+			Peer:   &actx.Peer,
+			Action: &tg.SendMessageTypingAction{},
+		})
+		if err != nil {
+			return xerrors.Errorf("set typing: %w", err)
+		}
+
+		if time.Now().After(deadline) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Quote replies with a random previous message sent by the target,
+// prefixed like a quote.
+type Quote struct{}
+
+// Apply implements Action.
+func (Quote) Apply(ctx context.Context, actx ActionContext) error {
+	history, err := actx.Troll.raw.MessagesGetHistory(ctx, &tg.MessagesGetHistoryRequest{
+		Peer:  &actx.Peer,
+		Limit: 50,
+	})
+	if err != nil {
+		return xerrors.Errorf("get history: %w", err)
+	}
+
+	messages, ok := history.(interface{ GetMessages() []tg.MessageClass })
+	if !ok {
+		return xerrors.Errorf("unexpected history type %T", history)
+	}
+
+	var candidates []string
+	for _, m := range messages.GetMessages() {
+		msg, ok := m.(*tg.Message)
+		if !ok || msg.Out || msg.Message == "" {
+			continue
+		}
+		candidates = append(candidates, msg.Message)
+	}
+	if len(candidates) == 0 {
+		return xerrors.Errorf("target %q: no previous message to quote", actx.Target.domain)
+	}
+
+	quote := candidates[actx.Rand.Intn(len(candidates))]
+	_, err = actx.Troll.sender.To(&actx.Peer).Reply(actx.Message.ID).Text(ctx, "> "+quote)
+	return err
+}
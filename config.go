@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/time/rate"
+	"golang.org/x/xerrors"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	defaultStickerProbability = 0.5
+	defaultRateEvery          = 15 * time.Second
+	defaultRateBurst          = 1
+)
+
+// TargetConfig is the on-disk representation of a single victim.
+type TargetConfig struct {
+	// Domain is the username (e.g. "some_user") passed to the message
+	// sender's resolver to resolve the target's peer. It is not looked up
+	// as a numeric user ID.
+	Domain string `yaml:"domain" json:"domain"`
+	// StickerSet overrides Config.DefaultStickerSet for this target.
+	StickerSet string `yaml:"sticker_set,omitempty" json:"sticker_set,omitempty"`
+	// Policy is one of "revoke", "sticker_or_revoke" or "ignore".
+	// Defaults to "sticker_or_revoke".
+	Policy string `yaml:"policy,omitempty" json:"policy,omitempty"`
+	// StickerProbability is used by the "sticker_or_revoke" policy.
+	// Defaults to 0.5 when nil. A pointer so an operator can explicitly
+	// set 0 ("never sticker, always revoke") without it being
+	// indistinguishable from the field being omitted.
+	StickerProbability *float64 `yaml:"sticker_probability,omitempty" json:"sticker_probability,omitempty"`
+	// RateEvery and RateBurst configure this target's rate limiter.
+	// Default to one message per 15 seconds.
+	RateEvery time.Duration `yaml:"rate_every,omitempty" json:"rate_every,omitempty"`
+	RateBurst int           `yaml:"rate_burst,omitempty" json:"rate_burst,omitempty"`
+}
+
+func (tc TargetConfig) limiter() *rate.Limiter {
+	every := tc.RateEvery
+	if every == 0 {
+		every = defaultRateEvery
+	}
+	burst := tc.RateBurst
+	if burst == 0 {
+		burst = defaultRateBurst
+	}
+	return rate.NewLimiter(rate.Every(every), burst)
+}
+
+// StorageConfig selects and configures the Storage backend used to persist
+// resolved peers, sticker sets, the seen-message dedupe window and
+// counters across restarts.
+type StorageConfig struct {
+	// Type is one of "memory" (the default), "bolt" or "badger".
+	Type string `yaml:"type,omitempty" json:"type,omitempty"`
+	// Path is the BoltDB file or BadgerDB directory. Required unless
+	// Type is "memory".
+	Path string `yaml:"path,omitempty" json:"path,omitempty"`
+	// ResolvedPeerTTL bounds how long a cached resolved peer is reused
+	// before setup re-resolves it over RPC. Zero means it never expires.
+	ResolvedPeerTTL time.Duration `yaml:"resolved_peer_ttl,omitempty" json:"resolved_peer_ttl,omitempty"`
+}
+
+// Config is the top-level, loadable troll configuration.
+type Config struct {
+	// DefaultStickerSet is used by targets that don't set their own.
+	DefaultStickerSet string         `yaml:"default_sticker_set,omitempty" json:"default_sticker_set,omitempty"`
+	Targets           []TargetConfig `yaml:"targets" json:"targets"`
+	Storage           StorageConfig  `yaml:"storage,omitempty" json:"storage,omitempty"`
+}
+
+// LoadConfig reads a Config from a YAML or JSON file, selected by its
+// extension (.yaml, .yml or .json).
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, xerrors.Errorf("read %q: %w", path, err)
+	}
+
+	var cfg Config
+	switch ext := filepath.Ext(path); ext {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return nil, xerrors.Errorf("unmarshal yaml: %w", err)
+		}
+	case ".json":
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return nil, xerrors.Errorf("unmarshal json: %w", err)
+		}
+	default:
+		return nil, xerrors.Errorf("unsupported config extension %q", ext)
+	}
+
+	if len(cfg.Targets) == 0 {
+		return nil, xerrors.Errorf("config %q defines no targets", path)
+	}
+
+	return &cfg, nil
+}
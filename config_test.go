@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestTargetConfigLimiterDefaults(t *testing.T) {
+	tc := TargetConfig{}
+	limiter := tc.limiter()
+
+	if burst := limiter.Burst(); burst != defaultRateBurst {
+		t.Fatalf("Burst() = %d, want %d", burst, defaultRateBurst)
+	}
+	if limit := limiter.Limit(); limit != rate.Every(defaultRateEvery) {
+		t.Fatalf("Limit() = %v, want %v", limit, rate.Every(defaultRateEvery))
+	}
+}
+
+func TestTargetConfigLimiterOverrides(t *testing.T) {
+	tc := TargetConfig{RateEvery: time.Second, RateBurst: 3}
+	limiter := tc.limiter()
+
+	if burst := limiter.Burst(); burst != 3 {
+		t.Fatalf("Burst() = %d, want 3", burst)
+	}
+	if limit := limiter.Limit(); limit != rate.Every(time.Second) {
+		t.Fatalf("Limit() = %v, want %v", limit, rate.Every(time.Second))
+	}
+}
@@ -2,12 +2,13 @@ package main
 
 import (
 	"context"
-	"math/rand"
 	"time"
 
 	"go.uber.org/zap"
 
 	"github.com/gotd/td/tg"
+
+	"github.com/tdakkota/trolljitrs/storage"
 )
 
 func (t *Troll) OnNewMessage(ctx context.Context, _ tg.Entities, update *tg.UpdateNewMessage) error {
@@ -21,42 +22,49 @@ func (t *Troll) OnNewMessage(ctx context.Context, _ tg.Entities, update *tg.Upda
 		return nil
 	}
 
-	resolved, ok := t.checkUserID(u.UserID)
+	tgt, resolved, ok := t.targets.Lookup(u.UserID)
 	if !ok {
 		return nil
 	}
 
 	t.logger.Info("Got message",
+		zap.String("domain", tgt.domain),
 		zap.String("text", msg.Message),
 		zap.Time("date", time.Unix(int64(msg.Date), 0)),
 	)
 
-	// #nosec G404
-	if sticker, ok := t.checkSticker(); ok && rand.Int()%2 == 0 {
-		return t.ignored(ctx, resolved, msg.ID, sticker)
+	if alreadySeen, err := t.store.SeenMessage(ctx, tgt.domain, msg.ID); err != nil {
+		t.logger.Warn("Seen-message check failed", zap.String("domain", tgt.domain), zap.Error(err))
+	} else if alreadySeen {
+		return nil
 	}
+	t.incrCounter(ctx, storage.CounterMessagesSeen)
 
-	return t.revoke(ctx, resolved, msg.ID)
-}
-
-func (t *Troll) ignored(ctx context.Context, resolved tg.InputPeerUser, msgID int, sticker tg.Document) error {
-	t.logger.Info("Answer sticker", zap.Int("msg_id", msgID))
-
-	_, err := t.sender.To(&resolved).
-		Reply(msgID).
-		Document(ctx, sticker.AsInputDocumentFileLocation())
-	return err
-}
-
-func (t *Troll) revoke(ctx context.Context, resolved tg.InputPeerUser, msgID int) error {
-	t.logger.Info("Delete message", zap.Int("msg_id", msgID))
-	self := t.sender.Self()
+	if allowed, retryAfter := t.checkRate(tgt); !allowed {
+		t.logger.Debug("Rate limited, dropping action",
+			zap.String("domain", tgt.domain),
+			zap.Duration("retry_after", retryAfter),
+		)
+		return nil
+	}
 
-	_, err := self.ForwardIDs(&resolved, msgID).Send(ctx)
-	if err != nil {
-		t.logger.Warn("Forward failed", zap.Error(err))
+	actx := ActionContext{
+		Troll:   t,
+		Target:  tgt,
+		Peer:    resolved,
+		Message: msg,
+		Logger:  t.logger,
+		Rand:    t.newActionRand(),
 	}
 
-	_, err = self.Revoke().Messages(ctx, msgID)
-	return err
+	for _, action := range tgt.policy.Select(actx) {
+		if err := action.Apply(ctx, actx); err != nil {
+			t.logger.Warn("Action failed",
+				zap.String("domain", tgt.domain),
+				zap.Error(err),
+			)
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/tdakkota/trolljitrs/storage"
+)
+
+// MetricsHandler renders store's counters in the Prometheus text
+// exposition format, under a "trolljitrs_" prefix.
+func MetricsHandler(store storage.Storage) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		counters, err := store.Counters(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		names := make([]string, 0, len(counters))
+		for name := range counters {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		for _, name := range names {
+			metric := "trolljitrs_" + name
+			fmt.Fprintf(w, "# TYPE %s counter\n%s %d\n", metric, metric, counters[name])
+		}
+	})
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// Policy selects zero or more actions to apply to an incoming message. A
+// target's Policy is consulted once per message, after rate limiting.
+type Policy interface {
+	Select(actx ActionContext) []Action
+}
+
+// PolicyFunc adapts a plain function to the Policy interface.
+type PolicyFunc func(actx ActionContext) []Action
+
+// Select implements Policy.
+func (f PolicyFunc) Select(actx ActionContext) []Action {
+	return f(actx)
+}
+
+// IgnorePolicy never selects an action.
+var IgnorePolicy Policy = PolicyFunc(func(ActionContext) []Action { return nil })
+
+// WeightedAction pairs an Action with its selection weight in a
+// weightedPolicy.
+type WeightedAction struct {
+	Action Action
+	Weight float64
+}
+
+// weightedPolicy picks exactly one action per message, at random,
+// proportional to its weight.
+type weightedPolicy struct {
+	actions []WeightedAction
+	total   float64
+}
+
+// NewWeightedPolicy builds a Policy that picks one of actions at random,
+// proportional to its weight. Actions with a weight <= 0 are dropped.
+func NewWeightedPolicy(actions ...WeightedAction) Policy {
+	p := &weightedPolicy{}
+	for _, a := range actions {
+		if a.Weight <= 0 {
+			continue
+		}
+		p.actions = append(p.actions, a)
+		p.total += a.Weight
+	}
+	return p
+}
+
+func (p *weightedPolicy) Select(actx ActionContext) []Action {
+	if len(p.actions) == 0 {
+		return nil
+	}
+
+	r := actx.Rand.Float64() * p.total
+	for _, a := range p.actions {
+		r -= a.Weight
+		if r <= 0 {
+			return []Action{a.Action}
+		}
+	}
+	return []Action{p.actions[len(p.actions)-1].Action}
+}
+
+// roundRobinPolicy cycles through actions in order, one per message.
+type roundRobinPolicy struct {
+	actions []Action
+	next    uint64
+}
+
+// NewRoundRobinPolicy builds a Policy that cycles through actions in order,
+// one per message.
+func NewRoundRobinPolicy(actions ...Action) Policy {
+	return &roundRobinPolicy{actions: actions}
+}
+
+func (p *roundRobinPolicy) Select(ActionContext) []Action {
+	if len(p.actions) == 0 {
+		return nil
+	}
+	i := atomic.AddUint64(&p.next, 1) - 1
+	return []Action{p.actions[i%uint64(len(p.actions))]}
+}
+
+// TimeWindow gates a Policy to a range of hours of the day, in [0, 24).
+// Windows that wrap past midnight (e.g. StartHour: 22, EndHour: 6) are
+// supported.
+type TimeWindow struct {
+	StartHour, EndHour int
+	Policy             Policy
+}
+
+// timeOfDayPolicy selects the first window whose hour range contains the
+// current hour, falling back to a default Policy if none match.
+type timeOfDayPolicy struct {
+	windows  []TimeWindow
+	fallback Policy
+}
+
+// NewTimeOfDayPolicy builds a Policy that delegates to the first matching
+// TimeWindow's Policy, or to fallback if none of windows match the current
+// hour. fallback may be nil, in which case no action is selected outside
+// the configured windows.
+func NewTimeOfDayPolicy(fallback Policy, windows ...TimeWindow) Policy {
+	return &timeOfDayPolicy{windows: windows, fallback: fallback}
+}
+
+func (p *timeOfDayPolicy) Select(actx ActionContext) []Action {
+	hour := time.Now().Hour()
+	for _, w := range p.windows {
+		if inHourWindow(hour, w.StartHour, w.EndHour) {
+			return w.Policy.Select(actx)
+		}
+	}
+	if p.fallback == nil {
+		return nil
+	}
+	return p.fallback.Select(actx)
+}
+
+func inHourWindow(hour, start, end int) bool {
+	if start <= end {
+		return hour >= start && hour < end
+	}
+	return hour >= start || hour < end
+}
+
+// buildPolicy constructs the Policy named by tc.Policy. Only the three
+// presets below can be selected from config; the round-robin and
+// time-of-day policies are not reachable through config or NewTroll.
+func buildPolicy(tc TargetConfig) (Policy, error) {
+	mode := tc.Policy
+	if mode == "" {
+		mode = "sticker_or_revoke"
+	}
+
+	switch mode {
+	case "ignore":
+		return IgnorePolicy, nil
+	case "revoke":
+		return NewWeightedPolicy(WeightedAction{Action: ForwardAndRevoke{}, Weight: 1}), nil
+	case "sticker_or_revoke":
+		probability := defaultStickerProbability
+		if tc.StickerProbability != nil {
+			probability = *tc.StickerProbability
+		}
+		return NewWeightedPolicy(
+			WeightedAction{Action: StickerReply{}, Weight: probability},
+			WeightedAction{Action: ForwardAndRevoke{}, Weight: 1 - probability},
+		), nil
+	default:
+		return nil, xerrors.Errorf("unknown policy %q", tc.Policy)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+)
+
+type namedAction struct{ name string }
+
+func (a namedAction) Apply(context.Context, ActionContext) error { return nil }
+
+func actionName(actions []Action) string {
+	if len(actions) != 1 {
+		return ""
+	}
+	return actions[0].(namedAction).name
+}
+
+func TestWeightedPolicyDropsNonPositiveWeights(t *testing.T) {
+	p := NewWeightedPolicy(
+		WeightedAction{Action: namedAction{"only"}, Weight: 1},
+		WeightedAction{Action: namedAction{"dropped"}, Weight: 0},
+	)
+	actx := ActionContext{Rand: rand.New(rand.NewSource(1))}
+
+	for i := 0; i < 10; i++ {
+		if got := actionName(p.Select(actx)); got != "only" {
+			t.Fatalf("Select() = %q, want %q", got, "only")
+		}
+	}
+}
+
+func TestWeightedPolicyEmptyActionsSelectsNothing(t *testing.T) {
+	p := NewWeightedPolicy()
+	actx := ActionContext{Rand: rand.New(rand.NewSource(1))}
+
+	if got := p.Select(actx); got != nil {
+		t.Fatalf("Select() = %v, want nil", got)
+	}
+}
+
+func TestRoundRobinPolicyCyclesInOrder(t *testing.T) {
+	p := NewRoundRobinPolicy(namedAction{"a"}, namedAction{"b"}, namedAction{"c"})
+
+	want := []string{"a", "b", "c", "a", "b"}
+	for i, w := range want {
+		if got := actionName(p.Select(ActionContext{})); got != w {
+			t.Fatalf("Select() #%d = %q, want %q", i, got, w)
+		}
+	}
+}
+
+func TestRoundRobinPolicyEmptySelectsNothing(t *testing.T) {
+	p := NewRoundRobinPolicy()
+	if got := p.Select(ActionContext{}); got != nil {
+		t.Fatalf("Select() = %v, want nil", got)
+	}
+}
+
+func TestInHourWindow(t *testing.T) {
+	tests := []struct {
+		name             string
+		hour, start, end int
+		want             bool
+	}{
+		{"within non-wrapping window", 10, 9, 17, true},
+		{"outside non-wrapping window", 18, 9, 17, false},
+		{"at start boundary", 9, 9, 17, true},
+		{"at end boundary is exclusive", 17, 9, 17, false},
+		{"within wrapping window, late", 23, 22, 6, true},
+		{"within wrapping window, early", 3, 22, 6, true},
+		{"outside wrapping window", 12, 22, 6, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := inHourWindow(tt.hour, tt.start, tt.end); got != tt.want {
+				t.Fatalf("inHourWindow(%d, %d, %d) = %v, want %v", tt.hour, tt.start, tt.end, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeOfDayPolicyFallsBackWithNoWindows(t *testing.T) {
+	fallback := NewRoundRobinPolicy(namedAction{"fallback"})
+	p := NewTimeOfDayPolicy(fallback)
+
+	if got := actionName(p.Select(ActionContext{})); got != "fallback" {
+		t.Fatalf("Select() = %q, want %q", got, "fallback")
+	}
+}
+
+func TestTimeOfDayPolicyNoFallbackSelectsNothing(t *testing.T) {
+	p := NewTimeOfDayPolicy(nil)
+	if got := p.Select(ActionContext{}); got != nil {
+		t.Fatalf("Select() = %v, want nil", got)
+	}
+}
+
+func TestBuildPolicyStickerOrRevokeDefaultsProbability(t *testing.T) {
+	p, err := buildPolicy(TargetConfig{Policy: "sticker_or_revoke"})
+	if err != nil {
+		t.Fatalf("buildPolicy: %v", err)
+	}
+
+	wp, ok := p.(*weightedPolicy)
+	if !ok {
+		t.Fatalf("buildPolicy returned %T, want *weightedPolicy", p)
+	}
+	if wp.actions[0].Weight != defaultStickerProbability {
+		t.Fatalf("sticker weight = %v, want %v", wp.actions[0].Weight, defaultStickerProbability)
+	}
+}
+
+func TestBuildPolicyStickerOrRevokeExplicitZeroMeansNeverSticker(t *testing.T) {
+	zero := 0.0
+	p, err := buildPolicy(TargetConfig{Policy: "sticker_or_revoke", StickerProbability: &zero})
+	if err != nil {
+		t.Fatalf("buildPolicy: %v", err)
+	}
+	actx := ActionContext{Rand: rand.New(rand.NewSource(1))}
+
+	for i := 0; i < 10; i++ {
+		if _, ok := p.Select(actx)[0].(ForwardAndRevoke); !ok {
+			t.Fatalf("Select() with probability 0: got a sticker reply, want always ForwardAndRevoke")
+		}
+	}
+}
+
+func TestBuildPolicyUnknownReturnsError(t *testing.T) {
+	if _, err := buildPolicy(TargetConfig{Policy: "nonsense"}); err == nil {
+		t.Fatal("buildPolicy(\"nonsense\"): got nil error, want one")
+	}
+}
+
+func TestBuildPolicyIgnore(t *testing.T) {
+	p, err := buildPolicy(TargetConfig{Policy: "ignore"})
+	if err != nil {
+		t.Fatalf("buildPolicy: %v", err)
+	}
+	if got := p.Select(ActionContext{}); got != nil {
+		t.Fatalf("Select() = %v, want nil", got)
+	}
+}
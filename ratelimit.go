@@ -0,0 +1,76 @@
+package main
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+
+	"github.com/gotd/td/tgerr"
+)
+
+// WithLimiter sets a shared limiter for every target that doesn't have its
+// own rate_every/rate_burst override in config.
+func (t *Troll) WithLimiter(limiter *rate.Limiter) *Troll {
+	for _, tgt := range t.targets.All() {
+		if !tgt.customLimiter {
+			tgt.limiter = limiter
+		}
+	}
+	return t
+}
+
+// WithLimit sets an independent limiter, built from limit and burst, on
+// every target that doesn't have its own rate_every/rate_burst override in
+// config.
+func (t *Troll) WithLimit(limit rate.Limit, burst int) *Troll {
+	for _, tgt := range t.targets.All() {
+		if !tgt.customLimiter {
+			tgt.limiter = rate.NewLimiter(limit, burst)
+		}
+	}
+	return t
+}
+
+// checkRate reports whether tgt is currently allowed to receive an action,
+// honoring both its token bucket and any outstanding FLOOD_WAIT back-off.
+// When it isn't, it also returns how long the caller should wait before
+// trying again.
+func (t *Troll) checkRate(tgt *target) (bool, time.Duration) {
+	now := time.Now()
+	if until := tgt.getBlockedUntil(); until.After(now) {
+		return false, until.Sub(now)
+	}
+
+	r := tgt.limiter.ReserveN(now, 1)
+	if !r.OK() {
+		return false, 0
+	}
+	if delay := r.DelayFrom(now); delay > 0 {
+		r.Cancel()
+		return false, delay
+	}
+	return true, 0
+}
+
+// applyFloodWait inspects err for a Telegram FLOOD_WAIT response and, if
+// found, makes tgt's next checkRate fail until the server-suggested delay
+// has elapsed, so we back off cooperatively instead of hammering.
+func (t *Troll) applyFloodWait(tgt *target, err error) {
+	delay, ok := floodWaitDelay(err)
+	if !ok {
+		return
+	}
+
+	tgt.setBlockedUntil(time.Now().Add(delay))
+	t.logger.Debug("Got FLOOD_WAIT, backing off",
+		zap.String("domain", tgt.domain),
+		zap.Duration("delay", delay),
+	)
+}
+
+// floodWaitDelay extracts the back-off duration from a Telegram
+// FLOOD_WAIT/FLOOD_PREMIUM_WAIT RPC error, if err is one.
+func floodWaitDelay(err error) (time.Duration, bool) {
+	return tgerr.AsFloodWait(err)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+
+	"github.com/gotd/td/tgerr"
+)
+
+var errTest = errors.New("unrelated error")
+
+func TestFloodWaitDelay(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantDelay time.Duration
+		wantOK    bool
+	}{
+		{
+			name:      "flood wait",
+			err:       tgerr.New(420, "FLOOD_WAIT_5"),
+			wantDelay: 5 * time.Second,
+			wantOK:    true,
+		},
+		{
+			name: "other rpc error",
+			err:  tgerr.New(400, "PEER_ID_INVALID"),
+		},
+		{
+			name: "not an rpc error",
+			err:  errTest,
+		},
+		{
+			name: "nil error",
+			err:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay, ok := floodWaitDelay(tt.err)
+			if ok != tt.wantOK || delay != tt.wantDelay {
+				t.Fatalf("floodWaitDelay(%v) = (%v, %v), want (%v, %v)", tt.err, delay, ok, tt.wantDelay, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestCheckRateRespectsLimiter(t *testing.T) {
+	tgt := &target{limiter: rate.NewLimiter(rate.Every(time.Hour), 1)}
+
+	if allowed, _ := (&Troll{}).checkRate(tgt); !allowed {
+		t.Fatal("first checkRate: got not allowed, want allowed")
+	}
+	if allowed, retryAfter := (&Troll{}).checkRate(tgt); allowed || retryAfter <= 0 {
+		t.Fatalf("second checkRate: got (%v, %v), want (false, >0)", allowed, retryAfter)
+	}
+}
+
+func TestCheckRateRespectsBlockedUntil(t *testing.T) {
+	tgt := &target{limiter: rate.NewLimiter(rate.Inf, 1)}
+	tgt.setBlockedUntil(time.Now().Add(time.Minute))
+
+	if allowed, retryAfter := (&Troll{}).checkRate(tgt); allowed || retryAfter <= 0 {
+		t.Fatalf("checkRate while blocked = (%v, %v), want (false, >0)", allowed, retryAfter)
+	}
+}
+
+func TestApplyFloodWaitBlocksFutureChecks(t *testing.T) {
+	tr := &Troll{logger: zap.NewNop()}
+	tgt := &target{limiter: rate.NewLimiter(rate.Inf, 1)}
+
+	tr.applyFloodWait(tgt, tgerr.New(420, "FLOOD_WAIT_60"))
+
+	if allowed, retryAfter := tr.checkRate(tgt); allowed || retryAfter <= 0 {
+		t.Fatalf("checkRate after FLOOD_WAIT = (%v, %v), want (false, >0)", allowed, retryAfter)
+	}
+}
+
+func TestApplyFloodWaitIgnoresUnrelatedErrors(t *testing.T) {
+	tr := &Troll{logger: zap.NewNop()}
+	tgt := &target{limiter: rate.NewLimiter(rate.Inf, 1)}
+
+	tr.applyFloodWait(tgt, errTest)
+
+	if allowed, _ := tr.checkRate(tgt); !allowed {
+		t.Fatal("checkRate after unrelated error: got not allowed, want allowed")
+	}
+}
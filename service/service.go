@@ -0,0 +1,162 @@
+// Package service provides a small BaseService abstraction implementing
+// common Start/Stop/Wait lifecycle semantics for long-running components.
+package service
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"golang.org/x/xerrors"
+)
+
+// Hook is a lifecycle callback invoked during Start.
+type Hook func(ctx context.Context) error
+
+// BaseService implements Start/Stop/Wait/IsRunning semantics that embedders
+// can reuse instead of hand-rolling context plumbing and goroutine tracking.
+//
+// Start runs registered hooks synchronously and, once they succeed, marks
+// the service as running. Goroutines spawned afterwards via Go are bound to
+// an internal context cancelled by Stop, which then waits for them to exit
+// before returning. Stop is safe to call multiple times.
+type BaseService struct {
+	name   string
+	logger *zap.Logger
+
+	mux    sync.Mutex
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	started atomic.Bool
+	running atomic.Bool
+	stopped atomic.Bool
+
+	errOnce sync.Once
+	err     error
+
+	onStart []Hook
+	onStop  []func()
+}
+
+// NewBaseService creates a new BaseService with the given name, used only
+// for logging.
+func NewBaseService(name string, logger *zap.Logger) *BaseService {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &BaseService{
+		name:   name,
+		logger: logger,
+	}
+}
+
+// SetLogger replaces the logger used for lifecycle events.
+func (s *BaseService) SetLogger(logger *zap.Logger) {
+	s.logger = logger
+}
+
+// OnStart registers a hook to run synchronously, in registration order,
+// during Start. If a hook returns an error, Start aborts and returns that
+// error without running the remaining hooks.
+func (s *BaseService) OnStart(hook Hook) {
+	s.onStart = append(s.onStart, hook)
+}
+
+// OnStop registers a hook to run during Stop, after the internal context
+// has been cancelled and all goroutines spawned via Go have exited.
+func (s *BaseService) OnStop(hook func()) {
+	s.onStop = append(s.onStop, hook)
+}
+
+// Name returns the service name.
+func (s *BaseService) Name() string {
+	return s.name
+}
+
+// IsRunning reports whether Start has completed successfully and the
+// service has not yet been stopped.
+func (s *BaseService) IsRunning() bool {
+	return s.running.Load() && !s.stopped.Load()
+}
+
+// Start runs the registered OnStart hooks synchronously and, if all of them
+// succeed, marks the service as running. It is an error to call Start more
+// than once.
+func (s *BaseService) Start(ctx context.Context) error {
+	if !s.started.CompareAndSwap(false, true) {
+		return xerrors.Errorf("%s: already started", s.name)
+	}
+
+	s.mux.Lock()
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	s.mux.Unlock()
+
+	for _, hook := range s.onStart {
+		if err := hook(s.ctx); err != nil {
+			s.cancel()
+			return err
+		}
+	}
+
+	s.running.Store(true)
+	s.logger.Info("Service started", zap.String("service", s.name))
+	return nil
+}
+
+// Go spawns f in a tracked goroutine bound to the context passed to Start.
+// Stop waits for all such goroutines to return before it returns. The
+// first error reported by f, other than context cancellation, is later
+// returned from Wait.
+func (s *BaseService) Go(f Hook) {
+	s.mux.Lock()
+	ctx := s.ctx
+	s.mux.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if err := f(ctx); err != nil && ctx.Err() == nil {
+			s.errOnce.Do(func() {
+				s.err = err
+			})
+			s.logger.Warn("Tracked goroutine failed",
+				zap.String("service", s.name),
+				zap.Error(err),
+			)
+		}
+	}()
+}
+
+// Stop cancels the internal context and waits for all goroutines spawned
+// via Go to return, then runs the registered OnStop hooks. Stop is
+// idempotent: calls after the first are a no-op.
+func (s *BaseService) Stop() {
+	if !s.stopped.CompareAndSwap(false, true) {
+		return
+	}
+
+	s.mux.Lock()
+	cancel := s.cancel
+	s.mux.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+
+	s.wg.Wait()
+
+	for _, hook := range s.onStop {
+		hook()
+	}
+
+	s.logger.Info("Service stopped", zap.String("service", s.name))
+}
+
+// Wait blocks until all goroutines spawned via Go have returned and reports
+// the first error among them, if any.
+func (s *BaseService) Wait() error {
+	s.wg.Wait()
+	return s.err
+}
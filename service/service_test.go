@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBaseServiceStartRunsHooksAndMarksRunning(t *testing.T) {
+	s := NewBaseService("test", nil)
+
+	var ran bool
+	s.OnStart(func(ctx context.Context) error {
+		ran = true
+		return nil
+	})
+
+	if s.IsRunning() {
+		t.Fatal("IsRunning before Start: got true, want false")
+	}
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if !ran {
+		t.Fatal("OnStart hook was not run")
+	}
+	if !s.IsRunning() {
+		t.Fatal("IsRunning after Start: got false, want true")
+	}
+}
+
+func TestBaseServiceStartTwiceFails(t *testing.T) {
+	s := NewBaseService("test", nil)
+
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("first Start: %v", err)
+	}
+	if err := s.Start(context.Background()); err == nil {
+		t.Fatal("second Start: got nil error, want one")
+	}
+}
+
+func TestBaseServiceStartAbortsOnHookError(t *testing.T) {
+	s := NewBaseService("test", nil)
+
+	want := errors.New("boom")
+	var secondRan bool
+	s.OnStart(func(ctx context.Context) error { return want })
+	s.OnStart(func(ctx context.Context) error {
+		secondRan = true
+		return nil
+	})
+
+	if err := s.Start(context.Background()); !errors.Is(err, want) {
+		t.Fatalf("Start error = %v, want %v", err, want)
+	}
+	if secondRan {
+		t.Fatal("hook after the failing one should not have run")
+	}
+	if s.IsRunning() {
+		t.Fatal("IsRunning after failed Start: got true, want false")
+	}
+}
+
+func TestBaseServiceGoCancelledByStop(t *testing.T) {
+	s := NewBaseService("test", nil)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	done := make(chan struct{})
+	s.Go(func(ctx context.Context) error {
+		<-ctx.Done()
+		close(done)
+		return ctx.Err()
+	})
+
+	s.Stop()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("goroutine was not cancelled by Stop")
+	}
+	if s.IsRunning() {
+		t.Fatal("IsRunning after Stop: got true, want false")
+	}
+}
+
+func TestBaseServiceWaitReportsFirstGoError(t *testing.T) {
+	s := NewBaseService("test", nil)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	want := errors.New("failed")
+	s.Go(func(ctx context.Context) error { return want })
+	s.Go(func(ctx context.Context) error { return nil })
+
+	if err := s.Wait(); !errors.Is(err, want) {
+		t.Fatalf("Wait() = %v, want %v", err, want)
+	}
+}
+
+func TestBaseServiceStopRunsOnStopHooksAndIsIdempotent(t *testing.T) {
+	s := NewBaseService("test", nil)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	var mux sync.Mutex
+	calls := 0
+	s.OnStop(func() {
+		mux.Lock()
+		calls++
+		mux.Unlock()
+	})
+
+	s.Stop()
+	s.Stop()
+
+	mux.Lock()
+	defer mux.Unlock()
+	if calls != 1 {
+		t.Fatalf("OnStop hook ran %d times, want 1", calls)
+	}
+}
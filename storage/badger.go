@@ -0,0 +1,234 @@
+package storage
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"strings"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v3"
+	"golang.org/x/xerrors"
+
+	"github.com/gotd/td/tg"
+)
+
+const (
+	badgerPrefixPeer    = "peer:"
+	badgerPrefixSticker = "sticker:"
+	badgerPrefixSeen    = "seen:"
+	badgerPrefixCounter = "counter:"
+)
+
+// BadgerStorage is a Storage backed by a BadgerDB directory.
+type BadgerStorage struct {
+	db *badger.DB
+}
+
+// OpenBadgerStorage opens (creating if necessary) the BadgerDB directory
+// at path.
+func OpenBadgerStorage(path string) (*BadgerStorage, error) {
+	db, err := badger.Open(badger.DefaultOptions(path))
+	if err != nil {
+		return nil, xerrors.Errorf("open badger db %q: %w", path, err)
+	}
+	return &BadgerStorage{db: db}, nil
+}
+
+func badgerGet(db *badger.DB, key string, out interface{}) (bool, error) {
+	found := false
+	err := db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(key))
+		if xerrors.Is(err, badger.ErrKeyNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			if err := json.Unmarshal(val, out); err != nil {
+				return xerrors.Errorf("decode %q: %w", key, err)
+			}
+			found = true
+			return nil
+		})
+	})
+	return found, err
+}
+
+func badgerSet(db *badger.DB, key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return xerrors.Errorf("encode %q: %w", key, err)
+	}
+	return db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(key), data)
+	})
+}
+
+// badgerGetBytes returns the raw value stored at key, if any.
+func badgerGetBytes(db *badger.DB, key string) ([]byte, bool, error) {
+	var (
+		data  []byte
+		found bool
+	)
+	err := db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(key))
+		if xerrors.Is(err, badger.ErrKeyNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			data = append([]byte(nil), val...)
+			found = true
+			return nil
+		})
+	})
+	return data, found, err
+}
+
+// badgerSetBytes stores data at key verbatim.
+func badgerSetBytes(db *badger.DB, key string, data []byte) error {
+	return db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(key), data)
+	})
+}
+
+// ResolvedPeer implements Storage.
+func (s *BadgerStorage) ResolvedPeer(_ context.Context, domain string, ttl time.Duration) (tg.InputPeerUser, bool, error) {
+	var peer resolvedPeer
+	found, err := badgerGet(s.db, badgerPrefixPeer+domain, &peer)
+	if err != nil || !found || peer.expired(ttl) {
+		return tg.InputPeerUser{}, false, err
+	}
+	return peer.Peer, true, nil
+}
+
+// SetResolvedPeer implements Storage.
+func (s *BadgerStorage) SetResolvedPeer(_ context.Context, domain string, peer tg.InputPeerUser) error {
+	return badgerSet(s.db, badgerPrefixPeer+domain, resolvedPeer{Peer: peer, FetchedAt: time.Now()})
+}
+
+// StickerSet implements Storage.
+//
+// Sticker documents are stored as gotd bin-encoded bytes rather than JSON,
+// via badgerGetBytes/decodeDocuments: tg.Document.Attributes is a
+// []tg.DocumentAttributeClass, and encoding/json cannot unmarshal back into
+// an interface-typed field.
+func (s *BadgerStorage) StickerSet(_ context.Context, shortName string) ([]tg.Document, bool, error) {
+	data, found, err := badgerGetBytes(s.db, badgerPrefixSticker+shortName)
+	if err != nil || !found {
+		return nil, found, err
+	}
+
+	docs, err := decodeDocuments(data)
+	if err != nil {
+		return nil, false, xerrors.Errorf("decode sticker set %q: %w", shortName, err)
+	}
+	return docs, true, nil
+}
+
+// SetStickerSet implements Storage.
+func (s *BadgerStorage) SetStickerSet(_ context.Context, shortName string, docs []tg.Document) error {
+	data, err := encodeDocuments(docs)
+	if err != nil {
+		return xerrors.Errorf("encode sticker set %q: %w", shortName, err)
+	}
+	return badgerSetBytes(s.db, badgerPrefixSticker+shortName, data)
+}
+
+// SeenMessage implements Storage.
+func (s *BadgerStorage) SeenMessage(_ context.Context, domain string, msgID int) (bool, error) {
+	var alreadySeen bool
+
+	err := s.db.Update(func(txn *badger.Txn) error {
+		key := []byte(badgerPrefixSeen + domain)
+
+		var snap seenSnapshot
+		item, err := txn.Get(key)
+		switch {
+		case xerrors.Is(err, badger.ErrKeyNotFound):
+		case err != nil:
+			return err
+		default:
+			if err := item.Value(func(val []byte) error {
+				return json.Unmarshal(val, &snap)
+			}); err != nil {
+				return xerrors.Errorf("decode seen ring %q: %w", domain, err)
+			}
+		}
+
+		ring := ringFromSnapshot(snap)
+		alreadySeen = ring.add(msgID)
+
+		data, err := json.Marshal(ring.snapshot())
+		if err != nil {
+			return xerrors.Errorf("encode seen ring %q: %w", domain, err)
+		}
+		return txn.Set(key, data)
+	})
+
+	return alreadySeen, err
+}
+
+// IncrCounter implements Storage.
+func (s *BadgerStorage) IncrCounter(_ context.Context, name string, delta uint64) (uint64, error) {
+	var total uint64
+
+	err := s.db.Update(func(txn *badger.Txn) error {
+		key := []byte(badgerPrefixCounter + name)
+
+		item, err := txn.Get(key)
+		switch {
+		case xerrors.Is(err, badger.ErrKeyNotFound):
+		case err != nil:
+			return err
+		default:
+			if err := item.Value(func(val []byte) error {
+				total = binary.BigEndian.Uint64(val)
+				return nil
+			}); err != nil {
+				return err
+			}
+		}
+
+		total += delta
+		buf := make([]byte, 8)
+		binary.BigEndian.PutUint64(buf, total)
+		return txn.Set(key, buf)
+	})
+
+	return total, err
+}
+
+// Counters implements Storage.
+func (s *BadgerStorage) Counters(_ context.Context) (map[string]uint64, error) {
+	out := make(map[string]uint64)
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		prefix := []byte(badgerPrefixCounter)
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			item := it.Item()
+			name := strings.TrimPrefix(string(item.Key()), badgerPrefixCounter)
+			if err := item.Value(func(val []byte) error {
+				out[name] = binary.BigEndian.Uint64(val)
+				return nil
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return out, err
+}
+
+// Close implements Storage.
+func (s *BadgerStorage) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	"go.etcd.io/bbolt"
+	"golang.org/x/xerrors"
+
+	"github.com/gotd/td/tg"
+)
+
+const (
+	boltBucketPeers    = "peers"
+	boltBucketStickers = "stickers"
+	boltBucketSeen     = "seen"
+	boltBucketCounters = "counters"
+)
+
+// BoltStorage is a Storage backed by a single BoltDB file.
+type BoltStorage struct {
+	db *bbolt.DB
+}
+
+// OpenBoltStorage opens (creating if necessary) the BoltDB file at path.
+func OpenBoltStorage(path string) (*BoltStorage, error) {
+	db, err := bbolt.Open(path, 0o600, nil)
+	if err != nil {
+		return nil, xerrors.Errorf("open bolt db %q: %w", path, err)
+	}
+
+	buckets := []string{boltBucketPeers, boltBucketStickers, boltBucketSeen, boltBucketCounters}
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, name := range buckets {
+			if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return xerrors.Errorf("create bucket %q: %w", name, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return &BoltStorage{db: db}, nil
+}
+
+// ResolvedPeer implements Storage.
+func (s *BoltStorage) ResolvedPeer(_ context.Context, domain string, ttl time.Duration) (tg.InputPeerUser, bool, error) {
+	var (
+		peer  resolvedPeer
+		found bool
+	)
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		v := tx.Bucket([]byte(boltBucketPeers)).Get([]byte(domain))
+		if v == nil {
+			return nil
+		}
+		if err := json.Unmarshal(v, &peer); err != nil {
+			return xerrors.Errorf("decode peer %q: %w", domain, err)
+		}
+		found = true
+		return nil
+	})
+	if err != nil || !found || peer.expired(ttl) {
+		return tg.InputPeerUser{}, false, err
+	}
+	return peer.Peer, true, nil
+}
+
+// SetResolvedPeer implements Storage.
+func (s *BoltStorage) SetResolvedPeer(_ context.Context, domain string, peer tg.InputPeerUser) error {
+	data, err := json.Marshal(resolvedPeer{Peer: peer, FetchedAt: time.Now()})
+	if err != nil {
+		return xerrors.Errorf("encode peer %q: %w", domain, err)
+	}
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte(boltBucketPeers)).Put([]byte(domain), data)
+	})
+}
+
+// StickerSet implements Storage.
+func (s *BoltStorage) StickerSet(_ context.Context, shortName string) ([]tg.Document, bool, error) {
+	var (
+		docs  []tg.Document
+		found bool
+	)
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		v := tx.Bucket([]byte(boltBucketStickers)).Get([]byte(shortName))
+		if v == nil {
+			return nil
+		}
+
+		decoded, err := decodeDocuments(v)
+		if err != nil {
+			return xerrors.Errorf("decode sticker set %q: %w", shortName, err)
+		}
+		docs = decoded
+		found = true
+		return nil
+	})
+	return docs, found, err
+}
+
+// SetStickerSet implements Storage.
+func (s *BoltStorage) SetStickerSet(_ context.Context, shortName string, docs []tg.Document) error {
+	data, err := encodeDocuments(docs)
+	if err != nil {
+		return xerrors.Errorf("encode sticker set %q: %w", shortName, err)
+	}
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte(boltBucketStickers)).Put([]byte(shortName), data)
+	})
+}
+
+// SeenMessage implements Storage.
+func (s *BoltStorage) SeenMessage(_ context.Context, domain string, msgID int) (bool, error) {
+	var alreadySeen bool
+
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(boltBucketSeen))
+
+		var snap seenSnapshot
+		if v := b.Get([]byte(domain)); v != nil {
+			if err := json.Unmarshal(v, &snap); err != nil {
+				return xerrors.Errorf("decode seen ring %q: %w", domain, err)
+			}
+		}
+
+		ring := ringFromSnapshot(snap)
+		alreadySeen = ring.add(msgID)
+
+		data, err := json.Marshal(ring.snapshot())
+		if err != nil {
+			return xerrors.Errorf("encode seen ring %q: %w", domain, err)
+		}
+		return b.Put([]byte(domain), data)
+	})
+
+	return alreadySeen, err
+}
+
+// IncrCounter implements Storage.
+func (s *BoltStorage) IncrCounter(_ context.Context, name string, delta uint64) (uint64, error) {
+	var total uint64
+
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(boltBucketCounters))
+		if v := b.Get([]byte(name)); v != nil {
+			total = binary.BigEndian.Uint64(v)
+		}
+		total += delta
+
+		buf := make([]byte, 8)
+		binary.BigEndian.PutUint64(buf, total)
+		return b.Put([]byte(name), buf)
+	})
+
+	return total, err
+}
+
+// Counters implements Storage.
+func (s *BoltStorage) Counters(_ context.Context) (map[string]uint64, error) {
+	out := make(map[string]uint64)
+
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte(boltBucketCounters)).ForEach(func(k, v []byte) error {
+			out[string(k)] = binary.BigEndian.Uint64(v)
+			return nil
+		})
+	})
+
+	return out, err
+}
+
+// Close implements Storage.
+func (s *BoltStorage) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gotd/td/tg"
+)
+
+// MapStorage is an in-memory Storage, useful for tests or single-process
+// runs where persistence across restarts isn't needed.
+type MapStorage struct {
+	mux      sync.Mutex
+	peers    map[string]resolvedPeer
+	stickers map[string][]tg.Document
+	seen     map[string]*seenRing
+	counters map[string]uint64
+}
+
+// NewMapStorage creates an empty MapStorage.
+func NewMapStorage() *MapStorage {
+	return &MapStorage{
+		peers:    make(map[string]resolvedPeer),
+		stickers: make(map[string][]tg.Document),
+		seen:     make(map[string]*seenRing),
+		counters: make(map[string]uint64),
+	}
+}
+
+// ResolvedPeer implements Storage.
+func (m *MapStorage) ResolvedPeer(_ context.Context, domain string, ttl time.Duration) (tg.InputPeerUser, bool, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	p, ok := m.peers[domain]
+	if !ok || p.expired(ttl) {
+		return tg.InputPeerUser{}, false, nil
+	}
+	return p.Peer, true, nil
+}
+
+// SetResolvedPeer implements Storage.
+func (m *MapStorage) SetResolvedPeer(_ context.Context, domain string, peer tg.InputPeerUser) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	m.peers[domain] = resolvedPeer{Peer: peer, FetchedAt: time.Now()}
+	return nil
+}
+
+// StickerSet implements Storage.
+func (m *MapStorage) StickerSet(_ context.Context, shortName string) ([]tg.Document, bool, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	docs, ok := m.stickers[shortName]
+	return docs, ok, nil
+}
+
+// SetStickerSet implements Storage.
+func (m *MapStorage) SetStickerSet(_ context.Context, shortName string, docs []tg.Document) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	m.stickers[shortName] = docs
+	return nil
+}
+
+// SeenMessage implements Storage.
+func (m *MapStorage) SeenMessage(_ context.Context, domain string, msgID int) (bool, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	ring, ok := m.seen[domain]
+	if !ok {
+		ring = newSeenRing()
+		m.seen[domain] = ring
+	}
+	return ring.add(msgID), nil
+}
+
+// IncrCounter implements Storage.
+func (m *MapStorage) IncrCounter(_ context.Context, name string, delta uint64) (uint64, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	m.counters[name] += delta
+	return m.counters[name], nil
+}
+
+// Counters implements Storage.
+func (m *MapStorage) Counters(_ context.Context) (map[string]uint64, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	out := make(map[string]uint64, len(m.counters))
+	for k, v := range m.counters {
+		out[k] = v
+	}
+	return out, nil
+}
+
+// Close implements Storage. MapStorage holds no external resources.
+func (m *MapStorage) Close() error {
+	return nil
+}
@@ -0,0 +1,154 @@
+// Package storage persists the state Troll would otherwise have to
+// rebuild on every restart: resolved peers, sticker set contents, a
+// dedupe window of recently-handled message IDs, and usage counters.
+package storage
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/tg"
+)
+
+// Counter names used by Troll. Implementations don't need to know these,
+// but callers share them so /metrics output is consistent regardless of
+// backend.
+const (
+	CounterMessagesSeen  = "messages_seen"
+	CounterRevokesIssued = "revokes_issued"
+	CounterStickersSent  = "stickers_sent"
+)
+
+// SeenWindow bounds how many recent message IDs are remembered per target
+// for idempotency across restarts.
+const SeenWindow = 256
+
+// Storage is the persistence layer backing Troll's setup and message
+// handling. Implementations must be safe for concurrent use.
+type Storage interface {
+	// ResolvedPeer returns the peer cached for domain, and whether it was
+	// stored more recently than ttl ago. A zero ttl means "any age".
+	ResolvedPeer(ctx context.Context, domain string, ttl time.Duration) (tg.InputPeerUser, bool, error)
+	// SetResolvedPeer caches peer for domain, timestamped with the
+	// current time.
+	SetResolvedPeer(ctx context.Context, domain string, peer tg.InputPeerUser) error
+
+	// StickerSet returns the last-fetched documents of the sticker set
+	// shortName.
+	StickerSet(ctx context.Context, shortName string) ([]tg.Document, bool, error)
+	// SetStickerSet caches the documents of the sticker set shortName.
+	SetStickerSet(ctx context.Context, shortName string, docs []tg.Document) error
+
+	// SeenMessage records msgID as handled for domain and reports whether
+	// it had already been recorded, so callers can skip re-handling it
+	// across restarts. Only the last SeenWindow IDs per domain are kept.
+	SeenMessage(ctx context.Context, domain string, msgID int) (alreadySeen bool, err error)
+
+	// IncrCounter adds delta to the named counter and returns its new
+	// total.
+	IncrCounter(ctx context.Context, name string, delta uint64) (uint64, error)
+	// Counters returns a snapshot of every counter touched so far.
+	Counters(ctx context.Context) (map[string]uint64, error)
+
+	// Close releases any resources held by the Storage.
+	Close() error
+}
+
+// seenSnapshot is the on-disk representation of a seenRing, used by the
+// BoltDB and BadgerDB implementations to persist it across restarts.
+type seenSnapshot struct {
+	IDs  []int `json:"ids"`
+	Next int   `json:"next"`
+}
+
+func ringFromSnapshot(snap seenSnapshot) *seenRing {
+	r := newSeenRing()
+	r.ids = append([]int(nil), snap.IDs...)
+	r.next = snap.Next
+	for _, id := range r.ids {
+		r.seen[id] = struct{}{}
+	}
+	return r
+}
+
+func (r *seenRing) snapshot() seenSnapshot {
+	return seenSnapshot{IDs: append([]int(nil), r.ids...), Next: r.next}
+}
+
+// encodeDocuments serializes docs using gotd's bin encoding rather than
+// encoding/json: tg.Document.Attributes is a []tg.DocumentAttributeClass,
+// and encoding/json cannot unmarshal back into an interface-typed field,
+// which made every real sticker (they all carry a DocumentAttributeSticker
+// attribute) a guaranteed decode failure.
+func encodeDocuments(docs []tg.Document) ([]byte, error) {
+	var b bin.Buffer
+	b.PutInt(len(docs))
+	for i := range docs {
+		if err := docs[i].Encode(&b); err != nil {
+			return nil, xerrors.Errorf("encode document %d: %w", i, err)
+		}
+	}
+	return b.Buf, nil
+}
+
+// decodeDocuments is the inverse of encodeDocuments.
+func decodeDocuments(data []byte) ([]tg.Document, error) {
+	b := bin.Buffer{Buf: data}
+	n, err := b.Int()
+	if err != nil {
+		return nil, xerrors.Errorf("decode document count: %w", err)
+	}
+
+	docs := make([]tg.Document, n)
+	for i := 0; i < n; i++ {
+		if err := docs[i].Decode(&b); err != nil {
+			return nil, xerrors.Errorf("decode document %d: %w", i, err)
+		}
+	}
+	return docs, nil
+}
+
+// resolvedPeer is the value cached by ResolvedPeer/SetResolvedPeer.
+type resolvedPeer struct {
+	Peer      tg.InputPeerUser
+	FetchedAt time.Time
+}
+
+func (p resolvedPeer) expired(ttl time.Duration) bool {
+	return ttl > 0 && time.Since(p.FetchedAt) > ttl
+}
+
+// seenRing is a fixed-size ring buffer of recently-seen message IDs, used
+// by every Storage implementation to cap memory/disk usage for the dedupe
+// window.
+type seenRing struct {
+	ids  []int
+	seen map[int]struct{}
+	next int
+}
+
+func newSeenRing() *seenRing {
+	return &seenRing{seen: make(map[int]struct{})}
+}
+
+// add reports whether msgID was already present, then records it,
+// evicting the oldest entry once the ring is full.
+func (r *seenRing) add(msgID int) bool {
+	if _, ok := r.seen[msgID]; ok {
+		return true
+	}
+
+	if len(r.ids) < SeenWindow {
+		r.ids = append(r.ids, msgID)
+	} else {
+		evicted := r.ids[r.next]
+		delete(r.seen, evicted)
+		r.ids[r.next] = msgID
+		r.next = (r.next + 1) % SeenWindow
+	}
+	r.seen[msgID] = struct{}{}
+	return false
+}
@@ -0,0 +1,219 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gotd/td/tg"
+)
+
+func sampleDocuments() []tg.Document {
+	return []tg.Document{
+		{
+			ID:         1,
+			AccessHash: 2,
+			MimeType:   "application/x-tgsticker",
+			Attributes: []tg.DocumentAttributeClass{
+				&tg.DocumentAttributeSticker{
+					Alt: "🙂",
+					Stickerset: &tg.InputStickerSetShortName{
+						ShortName: "pack",
+					},
+				},
+			},
+		},
+		{
+			ID:         3,
+			AccessHash: 4,
+			MimeType:   "image/webp",
+		},
+	}
+}
+
+func TestEncodeDecodeDocumentsRoundTrip(t *testing.T) {
+	want := sampleDocuments()
+
+	data, err := encodeDocuments(want)
+	if err != nil {
+		t.Fatalf("encodeDocuments: %v", err)
+	}
+
+	got, err := decodeDocuments(data)
+	if err != nil {
+		t.Fatalf("decodeDocuments: %v", err)
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("decodeDocuments returned %d documents, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].ID != want[i].ID || got[i].AccessHash != want[i].AccessHash || got[i].MimeType != want[i].MimeType {
+			t.Fatalf("document %d round-tripped as %+v, want %+v", i, got[i], want[i])
+		}
+		if len(got[i].Attributes) != len(want[i].Attributes) {
+			t.Fatalf("document %d has %d attributes, want %d", i, len(got[i].Attributes), len(want[i].Attributes))
+		}
+	}
+
+	sticker, ok := got[0].Attributes[0].(*tg.DocumentAttributeSticker)
+	if !ok {
+		t.Fatalf("document 0 attribute 0 is %T, want *tg.DocumentAttributeSticker", got[0].Attributes[0])
+	}
+	if sticker.Alt != "🙂" {
+		t.Fatalf("sticker.Alt = %q, want %q", sticker.Alt, "🙂")
+	}
+}
+
+func TestEncodeDecodeDocumentsEmpty(t *testing.T) {
+	data, err := encodeDocuments(nil)
+	if err != nil {
+		t.Fatalf("encodeDocuments(nil): %v", err)
+	}
+
+	got, err := decodeDocuments(data)
+	if err != nil {
+		t.Fatalf("decodeDocuments: %v", err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("decodeDocuments(nil) = %v, want empty", got)
+	}
+}
+
+func TestSeenRingAddReportsDuplicates(t *testing.T) {
+	r := newSeenRing()
+
+	if r.add(1) {
+		t.Fatal("first add(1): got already seen, want new")
+	}
+	if !r.add(1) {
+		t.Fatal("second add(1): got new, want already seen")
+	}
+	if r.add(2) {
+		t.Fatal("add(2): got already seen, want new")
+	}
+}
+
+func TestSeenRingEvictsOldestOnceFull(t *testing.T) {
+	r := newSeenRing()
+	for i := 0; i < SeenWindow; i++ {
+		if r.add(i) {
+			t.Fatalf("add(%d): got already seen, want new", i)
+		}
+	}
+
+	// The ring is now full; adding one more should evict id 0. Re-adding 0
+	// itself then evicts id 1, since the ring keeps advancing.
+	if r.add(SeenWindow) {
+		t.Fatalf("add(%d): got already seen, want new", SeenWindow)
+	}
+	if r.add(0) {
+		t.Fatal("add(0) after eviction: got already seen, want new (it should have been evicted)")
+	}
+	if !r.add(2) {
+		t.Fatal("add(2): got new, want already seen (still in the ring)")
+	}
+}
+
+func TestSeenRingSnapshotRoundTrip(t *testing.T) {
+	r := newSeenRing()
+	for i := 0; i < SeenWindow+5; i++ {
+		r.add(i)
+	}
+
+	restored := ringFromSnapshot(r.snapshot())
+	for i := 5; i < SeenWindow+5; i++ {
+		if !restored.add(i) {
+			t.Fatalf("restored ring: add(%d) = new, want already seen", i)
+		}
+	}
+	if restored.add(SeenWindow + 5) {
+		t.Fatal("restored ring: add of a genuinely new id reported already seen")
+	}
+}
+
+func TestResolvedPeerExpired(t *testing.T) {
+	fresh := resolvedPeer{FetchedAt: time.Now()}
+	if fresh.expired(time.Minute) {
+		t.Fatal("fresh peer reported expired")
+	}
+
+	stale := resolvedPeer{FetchedAt: time.Now().Add(-time.Hour)}
+	if !stale.expired(time.Minute) {
+		t.Fatal("stale peer reported not expired")
+	}
+
+	if stale.expired(0) {
+		t.Fatal("zero ttl should mean \"never expires\"")
+	}
+}
+
+func TestMapStorageResolvedPeerTTL(t *testing.T) {
+	ctx := context.Background()
+	m := NewMapStorage()
+
+	if err := m.SetResolvedPeer(ctx, "alice", tg.InputPeerUser{UserID: 1}); err != nil {
+		t.Fatalf("SetResolvedPeer: %v", err)
+	}
+
+	if _, ok, err := m.ResolvedPeer(ctx, "alice", time.Hour); err != nil || !ok {
+		t.Fatalf("ResolvedPeer within ttl = (_, %v, %v), want (_, true, nil)", ok, err)
+	}
+
+	m.mux.Lock()
+	p := m.peers["alice"]
+	p.FetchedAt = time.Now().Add(-time.Hour)
+	m.peers["alice"] = p
+	m.mux.Unlock()
+
+	if _, ok, err := m.ResolvedPeer(ctx, "alice", time.Minute); err != nil || ok {
+		t.Fatalf("ResolvedPeer past ttl = (_, %v, %v), want (_, false, nil)", ok, err)
+	}
+	if _, ok, err := m.ResolvedPeer(ctx, "alice", 0); err != nil || !ok {
+		t.Fatalf("ResolvedPeer with zero ttl = (_, %v, %v), want (_, true, nil)", ok, err)
+	}
+}
+
+func TestMapStorageIncrCounter(t *testing.T) {
+	ctx := context.Background()
+	m := NewMapStorage()
+
+	total, err := m.IncrCounter(ctx, "sent", 2)
+	if err != nil || total != 2 {
+		t.Fatalf("IncrCounter = (%d, %v), want (2, nil)", total, err)
+	}
+	total, err = m.IncrCounter(ctx, "sent", 3)
+	if err != nil || total != 5 {
+		t.Fatalf("IncrCounter = (%d, %v), want (5, nil)", total, err)
+	}
+
+	counters, err := m.Counters(ctx)
+	if err != nil {
+		t.Fatalf("Counters: %v", err)
+	}
+	if counters["sent"] != 5 {
+		t.Fatalf("Counters()[\"sent\"] = %d, want 5", counters["sent"])
+	}
+}
+
+func TestMapStorageStickerSetRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	m := NewMapStorage()
+
+	if _, ok, err := m.StickerSet(ctx, "pack"); err != nil || ok {
+		t.Fatalf("StickerSet before Set = (_, %v, %v), want (_, false, nil)", ok, err)
+	}
+
+	want := sampleDocuments()
+	if err := m.SetStickerSet(ctx, "pack", want); err != nil {
+		t.Fatalf("SetStickerSet: %v", err)
+	}
+
+	got, ok, err := m.StickerSet(ctx, "pack")
+	if err != nil || !ok {
+		t.Fatalf("StickerSet after Set = (_, %v, %v), want (_, true, nil)", ok, err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("StickerSet returned %d documents, want %d", len(got), len(want))
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"golang.org/x/xerrors"
+
+	"github.com/tdakkota/trolljitrs/storage"
+)
+
+// openStorage builds the Storage backend described by cfg.
+func openStorage(cfg StorageConfig) (storage.Storage, error) {
+	switch cfg.Type {
+	case "", "memory":
+		return storage.NewMapStorage(), nil
+	case "bolt":
+		if cfg.Path == "" {
+			return nil, xerrors.Errorf("bolt storage requires a path")
+		}
+		return storage.OpenBoltStorage(cfg.Path)
+	case "badger":
+		if cfg.Path == "" {
+			return nil, xerrors.Errorf("badger storage requires a path")
+		}
+		return storage.OpenBadgerStorage(cfg.Path)
+	default:
+		return nil, xerrors.Errorf("unknown storage type %q", cfg.Type)
+	}
+}
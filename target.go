@@ -0,0 +1,170 @@
+package main
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"golang.org/x/xerrors"
+
+	"github.com/gotd/td/tg"
+)
+
+// target is a single resolved victim tracked by Targets.
+type target struct {
+	domain     string
+	stickerSet string
+	policy     Policy
+
+	mux      sync.RWMutex
+	resolved *tg.InputPeerUser
+	stickers []tg.Document
+
+	// limiter throttles actions taken against this target. customLimiter
+	// records whether it came from explicit config, so a Troll-wide
+	// WithLimit/WithLimiter call doesn't clobber an operator's override.
+	limiter       *rate.Limiter
+	customLimiter bool
+
+	// blockedUntil is set whenever Telegram answers with a FLOOD_WAIT for
+	// this target, overriding limiter until the server-suggested delay
+	// has elapsed.
+	blockedUntil time.Time
+}
+
+// lastSticker returns the last sticker of the fetched set, matching the
+// set's own ordering.
+func (t *target) lastSticker() (tg.Document, bool) {
+	t.mux.RLock()
+	defer t.mux.RUnlock()
+
+	if len(t.stickers) == 0 {
+		return tg.Document{}, false
+	}
+	return t.stickers[len(t.stickers)-1], true
+}
+
+// randomSticker returns an arbitrary sticker of the fetched set.
+func (t *target) randomSticker(rng *rand.Rand) (tg.Document, bool) {
+	t.mux.RLock()
+	defer t.mux.RUnlock()
+
+	if len(t.stickers) == 0 {
+		return tg.Document{}, false
+	}
+	return t.stickers[rng.Intn(len(t.stickers))], true
+}
+
+func (t *target) setResolved(p *tg.InputPeerUser) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	t.resolved = p
+}
+
+func (t *target) setStickers(docs []tg.Document) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	t.stickers = docs
+}
+
+func (t *target) getBlockedUntil() time.Time {
+	t.mux.RLock()
+	defer t.mux.RUnlock()
+	return t.blockedUntil
+}
+
+func (t *target) setBlockedUntil(until time.Time) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	t.blockedUntil = until
+}
+
+// Targets is a registry of victims, keyed by resolved Telegram user ID once
+// they have been looked up.
+type Targets struct {
+	mux     sync.RWMutex
+	byID    map[int64]*target
+	entries []*target
+}
+
+// NewTargets creates an empty Targets registry.
+func NewTargets() *Targets {
+	return &Targets{byID: make(map[int64]*target)}
+}
+
+// add registers t, before it has necessarily been resolved.
+func (ts *Targets) add(t *target) {
+	ts.mux.Lock()
+	defer ts.mux.Unlock()
+	ts.entries = append(ts.entries, t)
+}
+
+// markResolved indexes t by its resolved user ID so Lookup can find it in
+// O(1).
+func (ts *Targets) markResolved(t *target, p *tg.InputPeerUser) {
+	t.setResolved(p)
+
+	ts.mux.Lock()
+	defer ts.mux.Unlock()
+	ts.byID[p.UserID] = t
+}
+
+// Lookup returns the target matching the given user ID, if it has been
+// resolved.
+func (ts *Targets) Lookup(id int64) (*target, tg.InputPeerUser, bool) {
+	ts.mux.RLock()
+	t, ok := ts.byID[id]
+	ts.mux.RUnlock()
+	if !ok {
+		return nil, tg.InputPeerUser{}, false
+	}
+
+	t.mux.RLock()
+	defer t.mux.RUnlock()
+	if t.resolved == nil {
+		return nil, tg.InputPeerUser{}, false
+	}
+	return t, *t.resolved, true
+}
+
+// All returns a snapshot of every registered target, resolved or not.
+func (ts *Targets) All() []*target {
+	ts.mux.RLock()
+	defer ts.mux.RUnlock()
+	return append([]*target(nil), ts.entries...)
+}
+
+// Len reports the number of registered targets.
+func (ts *Targets) Len() int {
+	ts.mux.RLock()
+	defer ts.mux.RUnlock()
+	return len(ts.entries)
+}
+
+// buildTargets constructs a Targets registry from the given config,
+// falling back to cfg.DefaultStickerSet and a default rate limit for
+// targets that don't override them.
+func buildTargets(cfg *Config) (*Targets, error) {
+	ts := NewTargets()
+	for i, tc := range cfg.Targets {
+		policy, err := buildPolicy(tc)
+		if err != nil {
+			return nil, xerrors.Errorf("target %d (%s): %w", i, tc.Domain, err)
+		}
+
+		stickerSet := tc.StickerSet
+		if stickerSet == "" {
+			stickerSet = cfg.DefaultStickerSet
+		}
+
+		ts.add(&target{
+			domain:        tc.Domain,
+			stickerSet:    stickerSet,
+			policy:        policy,
+			limiter:       tc.limiter(),
+			customLimiter: tc.RateEvery != 0 || tc.RateBurst != 0,
+		})
+	}
+	return ts, nil
+}
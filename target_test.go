@@ -0,0 +1,69 @@
+package main
+
+import "testing"
+
+func TestBuildTargetsAppliesDefaultStickerSetAndPolicy(t *testing.T) {
+	cfg := &Config{
+		DefaultStickerSet: "default-pack",
+		Targets: []TargetConfig{
+			{Domain: "alice"},
+			{Domain: "bob", StickerSet: "bob-pack", Policy: "revoke"},
+		},
+	}
+
+	ts, err := buildTargets(cfg)
+	if err != nil {
+		t.Fatalf("buildTargets: %v", err)
+	}
+	if got := ts.Len(); got != 2 {
+		t.Fatalf("Len() = %d, want 2", got)
+	}
+
+	all := ts.All()
+	if all[0].domain != "alice" || all[0].stickerSet != "default-pack" {
+		t.Fatalf("target 0 = %+v, want domain=alice stickerSet=default-pack", all[0])
+	}
+	if all[1].domain != "bob" || all[1].stickerSet != "bob-pack" {
+		t.Fatalf("target 1 = %+v, want domain=bob stickerSet=bob-pack", all[1])
+	}
+	if wp, ok := all[0].policy.(*weightedPolicy); !ok || len(wp.actions) != 2 {
+		t.Fatalf("target 0 policy = %#v, want default sticker_or_revoke with 2 actions", all[0].policy)
+	}
+	if wp, ok := all[1].policy.(*weightedPolicy); !ok || len(wp.actions) != 1 {
+		t.Fatalf("target 1 policy = %#v, want revoke with 1 action", all[1].policy)
+	}
+}
+
+func TestBuildTargetsMarksCustomLimiter(t *testing.T) {
+	cfg := &Config{
+		Targets: []TargetConfig{
+			{Domain: "alice"},
+			{Domain: "bob", RateBurst: 5},
+		},
+	}
+
+	ts, err := buildTargets(cfg)
+	if err != nil {
+		t.Fatalf("buildTargets: %v", err)
+	}
+
+	all := ts.All()
+	if all[0].customLimiter {
+		t.Fatal("target without rate overrides: got customLimiter true, want false")
+	}
+	if !all[1].customLimiter {
+		t.Fatal("target with RateBurst override: got customLimiter false, want true")
+	}
+}
+
+func TestBuildTargetsRejectsUnknownPolicy(t *testing.T) {
+	cfg := &Config{
+		Targets: []TargetConfig{
+			{Domain: "alice", Policy: "nonsense"},
+		},
+	}
+
+	if _, err := buildTargets(cfg); err == nil {
+		t.Fatal("buildTargets with unknown policy: got nil error, want one")
+	}
+}
@@ -2,130 +2,251 @@ package main
 
 import (
 	"context"
+	"math/rand"
 	"sync"
 	"time"
 
 	"go.uber.org/zap"
-	"golang.org/x/sync/errgroup"
-	"golang.org/x/time/rate"
 	"golang.org/x/xerrors"
 
 	"github.com/gotd/td/telegram/message"
 	"github.com/gotd/td/telegram/message/peer"
 	"github.com/gotd/td/tg"
+
+	"github.com/tdakkota/trolljitrs/service"
+	"github.com/tdakkota/trolljitrs/storage"
 )
 
+// maxConcurrentResolves bounds how many targets are resolved or have their
+// sticker set refreshed at once.
+const maxConcurrentResolves = 4
+
 type Troll struct {
-	domain, stickerSet string
+	*service.BaseService
 
-	resolved    *tg.InputPeerUser
-	resolvedMux sync.RWMutex
-	sticker     *tg.Document
-	stickerMux  sync.RWMutex
+	targets *Targets
 
 	raw    *tg.Client
 	sender *message.Sender
 	logger *zap.Logger
 
-	limiter *rate.Limiter
+	store           storage.Storage
+	resolvedPeerTTL time.Duration
+
+	// rngMux guards rng, which seeds a fresh *rand.Rand for every
+	// incoming message so Actions don't need to synchronize their own
+	// randomness.
+	rngMux sync.Mutex
+	rng    *rand.Rand
+
+	enableStatusLoop bool
 }
 
-func NewTroll(domain, stickerSet string, raw *tg.Client) *Troll {
-	return &Troll{
-		domain:     domain,
-		stickerSet: stickerSet,
-		raw:        raw,
-		sender:     message.NewSender(raw),
-		logger:     zap.NewNop(),
-		limiter:    rate.NewLimiter(rate.Every(15*time.Second), 1),
+// NewTroll builds a Troll that manages every target described by cfg.
+func NewTroll(cfg *Config, raw *tg.Client) (*Troll, error) {
+	targets, err := buildTargets(cfg)
+	if err != nil {
+		return nil, xerrors.Errorf("build targets: %w", err)
+	}
+
+	store, err := openStorage(cfg.Storage)
+	if err != nil {
+		return nil, xerrors.Errorf("open storage: %w", err)
+	}
+
+	t := &Troll{
+		targets:          targets,
+		raw:              raw,
+		sender:           message.NewSender(raw),
+		logger:           zap.NewNop(),
+		store:            store,
+		resolvedPeerTTL:  cfg.Storage.ResolvedPeerTTL,
+		rng:              rand.New(rand.NewSource(time.Now().UnixNano())),
+		enableStatusLoop: true,
+	}
+	t.BaseService = service.NewBaseService("troll", t.logger)
+	t.BaseService.OnStart(func(ctx context.Context) error {
+		if err := t.setup(ctx); err != nil {
+			return xerrors.Errorf("setup: %w", err)
+		}
+		return nil
+	})
+	t.BaseService.OnStop(func() {
+		if err := t.store.Close(); err != nil {
+			t.logger.Warn("Closing storage failed", zap.Error(err))
+		}
+	})
+	return t, nil
+}
+
+// incrCounter increments the named storage counter, logging rather than
+// failing the caller if the backend is unavailable.
+func (t *Troll) incrCounter(ctx context.Context, name string) {
+	if _, err := t.store.IncrCounter(ctx, name, 1); err != nil {
+		t.logger.Warn("Increment counter failed", zap.String("counter", name), zap.Error(err))
 	}
 }
 
 // WithLogger sets logger to use.
 func (t *Troll) WithLogger(logger *zap.Logger) *Troll {
 	t.logger = logger
+	t.BaseService.SetLogger(logger)
 	return t
 }
 
-func (t *Troll) checkUserID(id int64) (tg.InputPeerUser, bool) {
-	t.resolvedMux.RLock()
-	if t.resolved == nil {
-		t.resolvedMux.RUnlock()
-		return tg.InputPeerUser{}, false
-	}
-	resolved := *t.resolved
-	t.resolvedMux.RUnlock()
-
-	if resolved.UserID != id {
-		return tg.InputPeerUser{}, false
-	}
+// WithStatusLoop controls whether Start spawns the periodic online-status
+// loop. It is enabled by default.
+func (t *Troll) WithStatusLoop(enable bool) *Troll {
+	t.enableStatusLoop = enable
+	return t
+}
 
-	return resolved, true
+// newActionRand returns a *rand.Rand private to the caller, seeded from
+// t.rng, so concurrent Actions don't need to synchronize their own
+// randomness.
+func (t *Troll) newActionRand() *rand.Rand {
+	t.rngMux.Lock()
+	seed := t.rng.Int63()
+	t.rngMux.Unlock()
+	return rand.New(rand.NewSource(seed))
 }
 
-func (t *Troll) checkSticker() (tg.Document, bool) {
-	t.stickerMux.RLock()
-	if t.sticker == nil {
-		t.stickerMux.RUnlock()
-		return tg.Document{}, false
+// eachTarget runs fn for every target in t.targets with at most
+// maxConcurrentResolves running concurrently, logging and skipping
+// failures instead of aborting the rest.
+func (t *Troll) eachTarget(ctx context.Context, label string, fn func(ctx context.Context, tgt *target) error) {
+	sem := make(chan struct{}, maxConcurrentResolves)
+	var wg sync.WaitGroup
+
+	for _, tgt := range t.targets.All() {
+		tgt := tgt
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := fn(ctx, tgt); err != nil {
+				t.logger.Warn("Target action failed",
+					zap.String("action", label),
+					zap.String("domain", tgt.domain),
+					zap.Error(err),
+				)
+			}
+		}()
 	}
-	sticker := *t.sticker
-	t.stickerMux.RUnlock()
 
-	return sticker, true
+	wg.Wait()
 }
 
-func (t *Troll) getSticker(ctx context.Context) error {
-	set, err := t.raw.MessagesGetStickerSet(ctx, &tg.InputStickerSetShortName{
-		ShortName: t.stickerSet,
-	})
-	if err != nil {
-		return xerrors.Errorf("get sticker set %q", t.stickerSet)
-	}
+func (t *Troll) getStickers(ctx context.Context) error {
+	t.eachTarget(ctx, "get sticker", func(ctx context.Context, tgt *target) error {
+		if tgt.stickerSet == "" {
+			return nil
+		}
 
-	if len(set.Documents) < 1 {
-		return xerrors.Errorf("sticker set is empty %v", set)
-	}
+		if docs, ok, err := t.store.StickerSet(ctx, tgt.stickerSet); err != nil {
+			t.logger.Warn("Read cached sticker set failed",
+				zap.String("stickerset", tgt.stickerSet), zap.Error(err))
+		} else if ok {
+			tgt.setStickers(docs)
+			t.logger.Info("Using cached sticker set",
+				zap.String("domain", tgt.domain),
+				zap.String("stickerset", tgt.stickerSet),
+			)
+			return nil
+		}
 
-	sticker, ok := set.Documents[len(set.Documents)-1].AsNotEmpty()
-	if !ok {
-		return xerrors.Errorf("last sticker is empty document %v", set)
-	}
+		set, err := t.raw.MessagesGetStickerSet(ctx, &tg.InputStickerSetShortName{
+			ShortName: tgt.stickerSet,
+		})
+		if err != nil {
+			return xerrors.Errorf("get sticker set %q: %w", tgt.stickerSet, err)
+		}
 
-	t.stickerMux.Lock()
-	t.sticker = sticker
-	t.stickerMux.Unlock()
+		if len(set.Documents) < 1 {
+			return xerrors.Errorf("sticker set %q is empty", tgt.stickerSet)
+		}
 
-	t.logger.Info("Got sticker set", zap.String("stickerset", t.stickerSet))
+		docs := make([]tg.Document, 0, len(set.Documents))
+		for _, d := range set.Documents {
+			doc, ok := d.AsNotEmpty()
+			if !ok {
+				continue
+			}
+			docs = append(docs, *doc)
+		}
+		if len(docs) == 0 {
+			return xerrors.Errorf("sticker set %q has no usable documents", tgt.stickerSet)
+		}
+
+		tgt.setStickers(docs)
+		if err := t.store.SetStickerSet(ctx, tgt.stickerSet, docs); err != nil {
+			t.logger.Warn("Persist sticker set failed",
+				zap.String("stickerset", tgt.stickerSet), zap.Error(err))
+		}
+		t.logger.Info("Got sticker set",
+			zap.String("domain", tgt.domain),
+			zap.String("stickerset", tgt.stickerSet),
+		)
+		return nil
+	})
 	return nil
 }
 
-func (t *Troll) getUser(ctx context.Context) error {
-	p, err := t.sender.Resolve(t.domain, peer.OnlyUser).AsInputPeer(ctx)
-	if err != nil {
-		return xerrors.Errorf("resolve %q: %w", t.domain, err)
-	}
+func (t *Troll) getUsers(ctx context.Context) error {
+	var resolved int
+	var mux sync.Mutex
 
-	userPeer, ok := p.(*tg.InputPeerUser)
-	if !ok {
-		return xerrors.Errorf("unexpected type %T", p)
-	}
+	t.eachTarget(ctx, "resolve user", func(ctx context.Context, tgt *target) error {
+		if cached, ok, err := t.store.ResolvedPeer(ctx, tgt.domain, t.resolvedPeerTTL); err != nil {
+			t.logger.Warn("Read cached peer failed", zap.String("domain", tgt.domain), zap.Error(err))
+		} else if ok {
+			t.targets.markResolved(tgt, &cached)
+			t.logger.Info("Using cached peer", zap.String("domain", tgt.domain))
 
-	t.resolvedMux.Lock()
-	t.resolved = userPeer
-	t.resolvedMux.Unlock()
+			mux.Lock()
+			resolved++
+			mux.Unlock()
+			return nil
+		}
 
-	t.logger.Info("Got user", zap.String("user", t.domain))
+		p, err := t.sender.Resolve(tgt.domain, peer.OnlyUser).AsInputPeer(ctx)
+		if err != nil {
+			return xerrors.Errorf("resolve %q: %w", tgt.domain, err)
+		}
+
+		userPeer, ok := p.(*tg.InputPeerUser)
+		if !ok {
+			return xerrors.Errorf("unexpected type %T", p)
+		}
+
+		t.targets.markResolved(tgt, userPeer)
+		if err := t.store.SetResolvedPeer(ctx, tgt.domain, *userPeer); err != nil {
+			t.logger.Warn("Persist peer failed", zap.String("domain", tgt.domain), zap.Error(err))
+		}
+		t.logger.Info("Got user", zap.String("domain", tgt.domain))
+
+		mux.Lock()
+		resolved++
+		mux.Unlock()
+		return nil
+	})
+
+	if resolved == 0 && t.targets.Len() > 0 {
+		return xerrors.Errorf("no targets could be resolved")
+	}
 	return nil
 }
 
 func (t *Troll) setup(ctx context.Context) error {
-	if err := t.getUser(ctx); err != nil {
-		return xerrors.Errorf("get user: %w", err)
+	if err := t.getUsers(ctx); err != nil {
+		return xerrors.Errorf("get users: %w", err)
 	}
 
-	if err := t.getSticker(ctx); err != nil {
-		t.logger.Warn("Get sticker failed", zap.Error(err))
+	if err := t.getStickers(ctx); err != nil {
+		t.logger.Warn("Get stickers failed", zap.Error(err))
 	}
 
 	return nil
@@ -147,25 +268,55 @@ func (t *Troll) statusLoop(ctx context.Context) error {
 	}
 }
 
-func (t *Troll) Run(ctx context.Context, statusLoop bool) error {
-	g, ctx := errgroup.WithContext(ctx)
+// Start resolves every target and their sticker sets synchronously, so
+// callers know right away whether the troll can run, then spawns the
+// status loop (unless disabled via WithStatusLoop) as a goroutine tracked
+// by the embedded BaseService. Start returns once setup has completed; it
+// does not block for the lifetime of the service — use Wait for that.
+func (t *Troll) Start(ctx context.Context) error {
+	if err := t.BaseService.Start(ctx); err != nil {
+		return xerrors.Errorf("start: %w", err)
+	}
 
-	g.Go(func() error {
-		if err := t.setup(ctx); err != nil {
-			return xerrors.Errorf("setup: %w", err)
-		}
-		return nil
-	})
+	if t.enableStatusLoop {
+		t.BaseService.Go(t.statusLoop)
+	}
 
-	if statusLoop {
-		g.Go(func() error {
-			if err := t.statusLoop(ctx); err != nil {
-				return xerrors.Errorf("status loop: %w", err)
-			}
-			return nil
-		})
+	return nil
+}
+
+// replySticker answers msgID with sticker, backing off tgt's limiter on
+// FLOOD_WAIT. It is shared by the StickerReply and RandomStickerFromSet
+// actions.
+func (t *Troll) replySticker(ctx context.Context, tgt *target, resolved tg.InputPeerUser, msgID int, sticker tg.Document) error {
+	t.logger.Info("Answer sticker", zap.Int("msg_id", msgID))
+
+	_, err := t.sender.To(&resolved).
+		Reply(msgID).
+		Document(ctx, sticker.AsInputDocumentFileLocation())
+	t.applyFloodWait(tgt, err)
+	if err == nil {
+		t.incrCounter(ctx, storage.CounterStickersSent)
+	}
+	return err
+}
+
+// forwardAndRevoke forwards msgID to Saved Messages and then revokes it
+// for everyone, backing off tgt's limiter on FLOOD_WAIT.
+func (t *Troll) forwardAndRevoke(ctx context.Context, tgt *target, resolved tg.InputPeerUser, msgID int) error {
+	t.logger.Info("Delete message", zap.Int("msg_id", msgID))
+	self := t.sender.Self()
+
+	_, err := self.ForwardIDs(&resolved, msgID).Send(ctx)
+	if err != nil {
+		t.logger.Warn("Forward failed", zap.Error(err))
+		t.applyFloodWait(tgt, err)
 	}
 
-	<-ctx.Done()
-	return g.Wait()
+	_, err = self.Revoke().Messages(ctx, msgID)
+	t.applyFloodWait(tgt, err)
+	if err == nil {
+		t.incrCounter(ctx, storage.CounterRevokesIssued)
+	}
+	return err
 }